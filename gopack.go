@@ -1,17 +1,31 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/fs"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
 	"golang.org/x/mod/semver"
+	"golang.org/x/mod/sumdb/dirhash"
 	"golang.org/x/mod/zip"
 )
 
@@ -35,16 +49,26 @@ func main() {
 func run() error {
 	// flags
 	srcDir := flag.String("src", "", "path to module source directory (worktree root with go.mod)")
-	version := flag.String("version", "", "version tag to publish, e.g. v1.0.3")
+	version := flag.String("version", "", "version tag to publish, e.g. v1.0.3 (omit, or pass \"pseudo\", to synthesize a pseudo-version from the git worktree)")
 	outRoot := flag.String("out", "", "GOPROXY root dir, e.g. /tmp/goproxy")
+	serveAddr := flag.String("serve", "", "if set, serve -out as a GOPROXY HTTP proxy on this address instead of publishing (e.g. :8080)")
+	check := flag.Bool("check", false, "before publishing, verify the exported API delta against the base version requires no greater a semver bump than -version makes")
+	baseVersion := flag.String("base", "", "version to diff against for -check (default: the highest version already published)")
+	allowDirty := flag.Bool("allow-dirty", false, "when synthesizing a pseudo-version, allow a dirty git worktree (uses time.Now() and logs a warning; the version itself carries no dirty marker)")
+	modulesGlob := flag.String("modules", "", "glob matched against module subdirectories relative to -src (\".\" for -src itself), limiting which modules a monorepo publish covers (default: all discovered modules)")
 	flag.Parse()
 
-	if *srcDir == "" || *version == "" || *outRoot == "" {
-		flag.Usage()
-		return fmt.Errorf("required flags: -src, -version, -out")
+	if *serveAddr != "" {
+		if *outRoot == "" {
+			flag.Usage()
+			return fmt.Errorf("required flags: -out, -serve")
+		}
+		return serve(*serveAddr, *outRoot)
 	}
-	if !semver.IsValid(*version) {
-		return fmt.Errorf("invalid version %q (want semver like v1.2.3)", *version)
+
+	if *srcDir == "" || *outRoot == "" {
+		flag.Usage()
+		return fmt.Errorf("required flags: -src, -out")
 	}
 
 	absSrc, err := filepath.Abs(*srcDir)
@@ -52,8 +76,63 @@ func run() error {
 		return err
 	}
 
-	// read go.mod → module path
-	goModPath := filepath.Join(absSrc, "go.mod")
+	mods, err := discoverModules(absSrc)
+	if err != nil {
+		return err
+	}
+	if len(mods) == 0 {
+		return fmt.Errorf("no go.mod found under %s", absSrc)
+	}
+
+	published := 0
+	for _, m := range mods {
+		rel := "."
+		if m.tagPrefix != "" {
+			rel = strings.TrimSuffix(m.tagPrefix, "/")
+		}
+		match, err := filepath.Match(*modulesGlob, rel)
+		if err != nil {
+			return fmt.Errorf("invalid -modules pattern %q: %w", *modulesGlob, err)
+		}
+		if *modulesGlob != "" && !match {
+			continue
+		}
+		if err := publishModule(publishConfig{
+			dir:         m.dir,
+			tagPrefix:   m.tagPrefix,
+			version:     *version,
+			outRoot:     *outRoot,
+			check:       *check,
+			baseVersion: *baseVersion,
+			allowDirty:  *allowDirty,
+		}); err != nil {
+			return fmt.Errorf("publish %s: %w", m.dir, err)
+		}
+		published++
+	}
+	if published == 0 {
+		return fmt.Errorf("-modules %q matched none of the %d module(s) found under %s", *modulesGlob, len(mods), absSrc)
+	}
+	return nil
+}
+
+// publishConfig holds the per-module inputs to publishModule. version,
+// check, baseVersion, and allowDirty come straight from the command-line
+// flags and are shared across every module of a monorepo publish.
+type publishConfig struct {
+	dir         string // absolute directory containing go.mod
+	tagPrefix   string // e.g. "foo/bar/" for a monorepo submodule tag, "" at the repo root
+	version     string
+	outRoot     string
+	check       bool
+	baseVersion string
+	allowDirty  bool
+}
+
+// publishModule writes the proxy entry for one module: .mod, .info, .zip,
+// .ziphash, .modhash, @v/list, @latest, and the aggregate sum.txt.
+func publishModule(cfg publishConfig) error {
+	goModPath := filepath.Join(cfg.dir, "go.mod")
 	goModBytes, err := os.ReadFile(goModPath)
 	if err != nil {
 		return fmt.Errorf("read go.mod: %w", err)
@@ -67,29 +146,48 @@ func run() error {
 	}
 	modPath := modf.Module.Mod.Path
 
+	version := cfg.version
+	infoTime := time.Now().UTC()
+	if version == "" || version == "pseudo" {
+		pv, t, err := pseudoVersionFromGit(cfg.dir, modPath, cfg.tagPrefix, cfg.allowDirty)
+		if err != nil {
+			return fmt.Errorf("synthesize pseudo-version: %w", err)
+		}
+		version, infoTime = pv, t
+	}
+	if !semver.IsValid(version) {
+		return fmt.Errorf("invalid version %q (want semver like v1.2.3)", version)
+	}
+
 	// proxy layout: <out>/<escaped module>/@v/<escaped version>.{mod,info,zip}
 	escPath, err := module.EscapePath(modPath)
 	if err != nil {
 		return fmt.Errorf("escape module path: %w", err)
 	}
-	escVer, err := module.EscapeVersion(*version)
+	escVer, err := module.EscapeVersion(version)
 	if err != nil {
 		return fmt.Errorf("escape version: %w", err)
 	}
 
-	modDir := filepath.Join(*outRoot, escPath)
+	modDir := filepath.Join(cfg.outRoot, escPath)
 	atV := filepath.Join(modDir, "@v")
 	if err := os.MkdirAll(atV, dirPerm); err != nil {
 		return err
 	}
 
+	if cfg.check {
+		if err := checkCompat(cfg.dir, modPath, version, atV, cfg.baseVersion); err != nil {
+			return err
+		}
+	}
+
 	// .mod
 	if err := os.WriteFile(filepath.Join(atV, escVer+".mod"), goModBytes, filePerm); err != nil {
 		return err
 	}
 
 	// .info
-	ib, err := json.Marshal(info{Version: *version, Time: time.Now().UTC()})
+	ib, err := json.Marshal(info{Version: version, Time: infoTime})
 	if err != nil {
 		return err
 	}
@@ -107,7 +205,7 @@ func run() error {
 	defer func() { _ = os.Remove(tmpZip) }()
 	defer tmp.Close()
 
-	if err := zip.CreateFromDir(tmp, module.Version{Path: modPath, Version: *version}, absSrc); err != nil {
+	if err := zip.CreateFromDir(tmp, module.Version{Path: modPath, Version: version}, cfg.dir); err != nil {
 		return err
 	}
 	if err := tmp.Close(); err != nil {
@@ -118,6 +216,34 @@ func run() error {
 		return err
 	}
 
+	// @v/list and @latest
+	versions, err := appendVersionList(atV, version)
+	if err != nil {
+		return err
+	}
+	if err := writeLatest(atV, modDir, versions); err != nil {
+		return err
+	}
+
+	// .ziphash, .modhash, and the aggregate sum.txt
+	zipHash, err := dirhash.HashZip(zipFile, dirhash.Hash1)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(atV, escVer+".ziphash"), []byte(zipHash), filePerm); err != nil {
+		return err
+	}
+	modHash, err := hashGoMod(goModBytes)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(atV, escVer+".modhash"), []byte(modHash), filePerm); err != nil {
+		return err
+	}
+	if err := appendSumLines(cfg.outRoot, modPath, version, zipHash, modHash); err != nil {
+		return err
+	}
+
 	fmt.Printf("Wrote:\n  %s\n  %s\n  %s\n",
 		filepath.Join(atV, escVer+".mod"),
 		filepath.Join(atV, escVer+".info"),
@@ -125,3 +251,647 @@ func run() error {
 	)
 	return nil
 }
+
+// discoveredModule is one go.mod found under a -src tree.
+type discoveredModule struct {
+	dir       string // absolute directory containing go.mod
+	tagPrefix string // e.g. "foo/bar/" for a subdirectory module, "" at the repo root
+}
+
+// discoverModules walks root for go.mod files, skipping vendor/hidden/test
+// directories, and returns one discoveredModule per file found, ordered by
+// directory so that a single-module -src produces exactly one entry with
+// an empty tagPrefix.
+func discoverModules(root string) ([]discoveredModule, error) {
+	var mods []discoveredModule
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == root {
+				return nil
+			}
+			switch name := d.Name(); {
+			case name == "vendor" || name == "testdata" || name == ".git":
+				return fs.SkipDir
+			case strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"):
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		dir := filepath.Dir(p)
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		tagPrefix := ""
+		if rel != "." {
+			tagPrefix = filepath.ToSlash(rel) + "/"
+		}
+		mods = append(mods, discoveredModule{dir: dir, tagPrefix: tagPrefix})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].dir < mods[j].dir })
+	return mods, nil
+}
+
+// appendVersionList adds version to <atV>/list, deduplicates, sorts the
+// result by semver, and returns the resulting list.
+func appendVersionList(atV, version string) ([]string, error) {
+	listPath := filepath.Join(atV, "list")
+	existing, err := readVersionList(listPath)
+	if err != nil {
+		return nil, err
+	}
+	versions := dedupeSortVersions(append(existing, version))
+
+	var b bytes.Buffer
+	for _, v := range versions {
+		b.WriteString(v)
+		b.WriteByte('\n')
+	}
+	if err := os.WriteFile(listPath, b.Bytes(), filePerm); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func readVersionList(listPath string) ([]string, error) {
+	data, err := os.ReadFile(listPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out, nil
+}
+
+func dedupeSortVersions(versions []string) []string {
+	seen := make(map[string]bool, len(versions))
+	out := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return semver.Compare(out[i], out[j]) < 0 })
+	return out
+}
+
+// writeLatest rewrites <modDir>/@latest with the info JSON of the highest
+// non-prerelease version in versions, falling back to the highest
+// prerelease when no non-prerelease version has been published yet.
+func writeLatest(atV, modDir string, versions []string) error {
+	latest := pickLatest(versions)
+	if latest == "" {
+		return nil
+	}
+	escVer, err := module.EscapeVersion(latest)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(atV, escVer+".info"))
+	if err != nil {
+		return fmt.Errorf("read info for latest version %s: %w", latest, err)
+	}
+	return os.WriteFile(filepath.Join(modDir, "@latest"), data, filePerm)
+}
+
+func pickLatest(versions []string) string {
+	var release, prerelease string
+	for _, v := range versions {
+		if semver.Prerelease(v) == "" {
+			if release == "" || semver.Compare(v, release) > 0 {
+				release = v
+			}
+		} else if prerelease == "" || semver.Compare(v, prerelease) > 0 {
+			prerelease = v
+		}
+	}
+	if release != "" {
+		return release
+	}
+	return prerelease
+}
+
+// hashGoMod computes the h1: dirhash of go.mod content, the same way the go
+// command computes it when verifying a module's go.mod against go.sum.
+func hashGoMod(data []byte) (string, error) {
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+// appendSumLines appends the h1: hashes for modPath@version and its go.mod
+// to <outRoot>/sum.txt, deduplicating and keeping the file sorted.
+func appendSumLines(outRoot, modPath, version, zipHash, modHash string) error {
+	sumPath := filepath.Join(outRoot, "sum.txt")
+	existing, err := readSumLines(sumPath)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(existing)+2)
+	out := make([]string, 0, len(existing)+2)
+	for _, l := range existing {
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	for _, l := range []string{
+		fmt.Sprintf("%s %s %s", modPath, version, zipHash),
+		fmt.Sprintf("%s %s/go.mod %s", modPath, version, modHash),
+	} {
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	sort.Strings(out)
+
+	var b bytes.Buffer
+	for _, l := range out {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return os.WriteFile(sumPath, b.Bytes(), filePerm)
+}
+
+// serve exposes outRoot over HTTP, implementing the GOPROXY protocol that
+// run() populates: @v/list, @v/<ver>.info, @v/<ver>.mod, @v/<ver>.zip,
+// @v/<ver>.ziphash, and @latest.
+func serve(addr, outRoot string) error {
+	log.Printf("serving %s as a GOPROXY on %s", outRoot, addr)
+	return http.ListenAndServe(addr, proxyHandler(outRoot))
+}
+
+func proxyHandler(outRoot string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		escPath, rest, ok := splitProxyPath(r.URL.Path)
+		if !ok || !validProxyRest(rest) {
+			proxyNotFound(w, r.URL.Path)
+			return
+		}
+		if _, err := module.UnescapePath(escPath); err != nil {
+			proxyNotFound(w, r.URL.Path)
+			return
+		}
+
+		data, err := os.ReadFile(filepath.Join(outRoot, escPath, rest))
+		if errors.Is(err, os.ErrNotExist) {
+			proxyNotFound(w, r.URL.Path)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", proxyContentType(rest))
+		w.Write(data)
+	})
+}
+
+// splitProxyPath splits a request path of the form <escModPath>/@v/... or
+// <escModPath>/@latest into the module path and the remaining on-disk
+// relative path under it.
+func splitProxyPath(urlPath string) (escModPath, rest string, ok bool) {
+	p := strings.TrimPrefix(urlPath, "/")
+	if i := strings.LastIndex(p, "/@v/"); i >= 0 {
+		return p[:i], "@v/" + p[i+len("/@v/"):], true
+	}
+	if strings.HasSuffix(p, "/@latest") {
+		return strings.TrimSuffix(p, "/@latest"), "@latest", true
+	}
+	return "", "", false
+}
+
+// validProxyRest reports whether rest is one of the exact on-disk shapes
+// run() ever produces under a module directory: "@v/list", "@latest", or
+// "@v/<name>" with no path separators, so that a request like
+// "/@v/../../../etc/passwd" can never escape outRoot.
+func validProxyRest(rest string) bool {
+	if rest == "@latest" || rest == "@v/list" {
+		return true
+	}
+	name, ok := strings.CutPrefix(rest, "@v/")
+	if !ok || name == "" || strings.ContainsAny(name, "/\\") {
+		return false
+	}
+	switch {
+	case strings.HasSuffix(name, ".info"), strings.HasSuffix(name, ".mod"),
+		strings.HasSuffix(name, ".zip"), strings.HasSuffix(name, ".ziphash"):
+		return true
+	default:
+		return false
+	}
+}
+
+func proxyContentType(rest string) string {
+	switch {
+	case rest == "@latest", strings.HasSuffix(rest, ".info"):
+		return "application/json"
+	case rest == "@v/list", strings.HasSuffix(rest, ".mod"), strings.HasSuffix(rest, ".ziphash"):
+		return "text/plain; charset=utf-8"
+	case strings.HasSuffix(rest, ".zip"):
+		return "application/zip"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func proxyNotFound(w http.ResponseWriter, urlPath string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprintf(w, "not found: %s\n", urlPath)
+}
+
+// pseudoVersionFromGit synthesizes a pseudo-version for the commit checked
+// out in absSrc, in the form produced by cmd/go/internal/modfetch, along
+// with the commit time to use for the .info file. A dirty worktree is
+// rejected unless allowDirty is set, in which case time.Now() is used and
+// a warning is logged; the returned version carries no dirty marker.
+// tagPrefix restricts the search
+// for a preceding tag to a monorepo submodule's own tag namespace (e.g.
+// "foo/bar/"), or the bare "vX.Y.Z" namespace when empty.
+func pseudoVersionFromGit(absSrc, modPath, tagPrefix string, allowDirty bool) (string, time.Time, error) {
+	dirty, err := gitIsDirty(absSrc)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if dirty && !allowDirty {
+		return "", time.Time{}, fmt.Errorf("git worktree %s has uncommitted changes; pass -allow-dirty to publish anyway", absSrc)
+	}
+
+	hash, err := gitOutput(absSrc, "rev-parse", "HEAD")
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("git rev-parse HEAD: %w", err)
+	}
+	rev := hash
+	if len(rev) > 12 {
+		rev = rev[:12]
+	}
+
+	commitTime := time.Now().UTC()
+	if !dirty {
+		epoch, err := gitOutput(absSrc, "log", "-1", "--format=%ct", "HEAD")
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("git log -1 --format=%%ct: %w", err)
+		}
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("parse commit time %q: %w", epoch, err)
+		}
+		commitTime = time.Unix(sec, 0).UTC()
+	}
+
+	older := ""
+	if tag, err := gitOutput(absSrc, "describe", "--tags", "--abbrev=0", "--match", tagPrefix+"v[0-9]*", "HEAD"); err == nil {
+		if bare := strings.TrimPrefix(tag, tagPrefix); semver.IsValid(bare) {
+			older = bare
+		}
+	}
+
+	pv := module.PseudoVersion(moduleMajor(modPath), older, commitTime, rev)
+	if dirty {
+		// zip.CreateFromDir requires a canonical version, so a dirty build can't be
+		// marked by mangling pv with a "+dirty" build tag; log it instead.
+		log.Printf("warning: publishing %s from a dirty worktree (-allow-dirty)", pv)
+	}
+	return pv, commitTime, nil
+}
+
+// moduleMajor returns the "vN" major version a module path declares (via a
+// trailing "/vN" path element), or "v0" if it declares none.
+func moduleMajor(modPath string) string {
+	if i := strings.LastIndex(modPath, "/v"); i >= 0 && semver.IsValid(modPath[i+1:]) {
+		return modPath[i+1:]
+	}
+	return "v0"
+}
+
+func gitIsDirty(dir string) (bool, error) {
+	out, err := gitOutput(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkCompat enforces that publishing version over baseVersion (or, if
+// baseVersion is empty, the highest version already in atV/list) makes at
+// least as large a semver bump as the exported API delta requires, in the
+// spirit of golang.org/x/exp/cmd/gorelease. It also checks that a v2+
+// module path carries the matching /vN suffix.
+func checkCompat(absSrc, modPath, version, atV, baseVersion string) error {
+	if major := semver.Major(version); major != "v0" && major != "v1" {
+		if !strings.HasSuffix(modPath, "/"+major) {
+			return fmt.Errorf("module path %q must end in %q for version %s", modPath, "/"+major, version)
+		}
+	}
+
+	if baseVersion == "" {
+		versions, err := readVersionList(filepath.Join(atV, "list"))
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return nil // nothing published yet; nothing to compare against
+		}
+		baseVersion = versions[len(versions)-1]
+	}
+
+	escBaseVer, err := module.EscapeVersion(baseVersion)
+	if err != nil {
+		return err
+	}
+	baseDir, err := os.MkdirTemp("", "gopack-base-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(baseDir)
+	baseZip := filepath.Join(atV, escBaseVer+".zip")
+	if err := zip.Unzip(baseDir, module.Version{Path: modPath, Version: baseVersion}, baseZip); err != nil {
+		return fmt.Errorf("extract base version %s: %w", baseVersion, err)
+	}
+
+	baseAPI, err := collectPackageAPI(baseDir)
+	if err != nil {
+		return fmt.Errorf("load exported API of base version %s: %w", baseVersion, err)
+	}
+	candidateAPI, err := collectPackageAPI(absSrc)
+	if err != nil {
+		return fmt.Errorf("load exported API of %s: %w", version, err)
+	}
+
+	added, removed, changed := diffAPI(baseAPI, candidateAPI)
+	required := requiredBump(added, removed, changed)
+	actual := semverBump(baseVersion, version)
+	if bumpRank[actual] < bumpRank[required] {
+		offenders := append(append([]string{}, removed...), changed...)
+		if len(offenders) == 0 {
+			offenders = added
+		}
+		return fmt.Errorf("%s over base %s is only a %s bump, but the API change requires a %s bump; offending symbols: %s",
+			version, baseVersion, actual, required, strings.Join(offenders, ", "))
+	}
+	return nil
+}
+
+// apiSymbol is the recorded shape of one exported package-level symbol.
+type apiSymbol struct {
+	Kind string // "func", "method", "type", "const", or "var"
+	Sig  string // printed source of its type/signature
+}
+
+// collectPackageAPI walks rootDir and returns the exported API surface of
+// every non-internal, non-main package, keyed by "<pkg dir>.<Name>" (or
+// "<pkg dir>.<Recv>.<Name>" for methods on an exported receiver type), with
+// the "<pkg dir>." part left off for the module root package.
+func collectPackageAPI(rootDir string) (map[string]apiSymbol, error) {
+	api := make(map[string]apiSymbol)
+	err := filepath.WalkDir(rootDir, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if dir != rootDir {
+			switch name := d.Name(); {
+			case name == "internal" || name == "testdata" || name == "vendor" || name == ".git":
+				return fs.SkipDir
+			case strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"):
+				return fs.SkipDir
+			}
+			// A subdirectory with its own go.mod is a separate module, published
+			// on its own; zip.CreateFromDir excludes it from this module's zip the
+			// same way, so its API must not leak into this module's either.
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				return fs.SkipDir
+			}
+		}
+
+		fset := token.NewFileSet()
+		pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+			return !strings.HasSuffix(fi.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", dir, err)
+		}
+		rel, err := filepath.Rel(rootDir, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for name, pkg := range pkgs {
+			if name == "main" {
+				continue
+			}
+			for _, file := range pkg.Files {
+				collectFileAPI(fset, rel, file, api)
+			}
+		}
+		return nil
+	})
+	return api, err
+}
+
+func collectFileAPI(fset *token.FileSet, pkgRel string, file *ast.File, api map[string]apiSymbol) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			key := apiKey(pkgRel, d.Name.Name)
+			kind := "func"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recv := receiverTypeName(d.Recv.List[0].Type)
+				if !ast.IsExported(recv) || !d.Name.IsExported() {
+					continue
+				}
+				key = apiKey(pkgRel, recv, d.Name.Name)
+				kind = "method"
+			} else if !d.Name.IsExported() {
+				continue
+			}
+			api[key] = apiSymbol{Kind: kind, Sig: funcSig(fset, d.Type)}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if !s.Name.IsExported() {
+						continue
+					}
+					api[apiKey(pkgRel, s.Name.Name)] = apiSymbol{Kind: "type", Sig: printNode(fset, s.Type)}
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for i, name := range s.Names {
+						if !name.IsExported() {
+							continue
+						}
+						sig := ""
+						switch {
+						case s.Type != nil:
+							sig = printNode(fset, s.Type)
+						case i < len(s.Values):
+							sig = printNode(fset, s.Values[i])
+						}
+						api[apiKey(pkgRel, name.Name)] = apiSymbol{Kind: kind, Sig: sig}
+					}
+				}
+			}
+		}
+	}
+}
+
+// apiKey joins a package-relative directory ("." for the module root) with
+// one or more name components into a single dotted key, without leaving a
+// leading dot for the root package.
+func apiKey(pkgRel string, names ...string) string {
+	parts := make([]string, 0, len(names)+1)
+	if pkgRel != "." {
+		parts = append(parts, pkgRel)
+	}
+	parts = append(parts, names...)
+	return strings.Join(parts, ".")
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// funcSig prints a function's signature for compatibility comparison,
+// omitting parameter, result, and type-parameter names: renaming a
+// parameter is not an API break, and shouldn't be reported as one.
+func funcSig(fset *token.FileSet, t *ast.FuncType) string {
+	stripped := &ast.FuncType{
+		TypeParams: stripFieldNames(t.TypeParams),
+		Params:     stripFieldNames(t.Params),
+		Results:    stripFieldNames(t.Results),
+	}
+	return printNode(fset, stripped)
+}
+
+func stripFieldNames(fl *ast.FieldList) *ast.FieldList {
+	if fl == nil {
+		return nil
+	}
+	fields := make([]*ast.Field, 0, len(fl.List))
+	for _, f := range fl.List {
+		// A grouped field like "a, b int" stands for one entry per name.
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for j := 0; j < n; j++ {
+			fields = append(fields, &ast.Field{Type: f.Type})
+		}
+	}
+	return &ast.FieldList{List: fields}
+}
+
+func printNode(fset *token.FileSet, n ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, n); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// diffAPI compares two exported API surfaces and classifies the delta.
+func diffAPI(base, candidate map[string]apiSymbol) (added, removed, changed []string) {
+	for key := range candidate {
+		if _, ok := base[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	for key, b := range base {
+		c, ok := candidate[key]
+		if !ok {
+			removed = append(removed, key)
+		} else if c.Sig != b.Sig {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+var bumpRank = map[string]int{"patch": 0, "minor": 1, "major": 2}
+
+func requiredBump(added, removed, changed []string) string {
+	if len(removed) > 0 || len(changed) > 0 {
+		return "major"
+	}
+	if len(added) > 0 {
+		return "minor"
+	}
+	return "patch"
+}
+
+func semverBump(base, version string) string {
+	if semver.Major(base) != semver.Major(version) {
+		return "major"
+	}
+	if semver.MajorMinor(base) != semver.MajorMinor(version) {
+		return "minor"
+	}
+	return "patch"
+}
+
+func readSumLines(sumPath string) ([]string, error) {
+	data, err := os.ReadFile(sumPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitProxyPath(t *testing.T) {
+	cases := []struct {
+		path        string
+		wantEscPath string
+		wantRest    string
+		wantOK      bool
+	}{
+		{"/example.com/mymod/@v/list", "example.com/mymod", "@v/list", true},
+		{"/example.com/mymod/@v/v1.0.0.info", "example.com/mymod", "@v/v1.0.0.info", true},
+		{"/example.com/mymod/@latest", "example.com/mymod", "@latest", true},
+		{"/example.com/mymod", "", "", false},
+		{"/example.com/mymod/@v/../../../../etc/passwd", "example.com/mymod", "@v/../../../../etc/passwd", true},
+	}
+	for _, c := range cases {
+		escPath, rest, ok := splitProxyPath(c.path)
+		if ok != c.wantOK || (ok && (escPath != c.wantEscPath || rest != c.wantRest)) {
+			t.Errorf("splitProxyPath(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				c.path, escPath, rest, ok, c.wantEscPath, c.wantRest, c.wantOK)
+		}
+	}
+}
+
+func TestValidProxyRest(t *testing.T) {
+	valid := []string{
+		"@latest",
+		"@v/list",
+		"@v/v1.0.0.info",
+		"@v/v1.0.0.mod",
+		"@v/v1.0.0.zip",
+		"@v/v1.0.0.ziphash",
+	}
+	for _, rest := range valid {
+		if !validProxyRest(rest) {
+			t.Errorf("validProxyRest(%q) = false, want true", rest)
+		}
+	}
+
+	invalid := []string{
+		"@v/../../../../etc/passwd",
+		"@v/sub/v1.0.0.info",
+		"@v/",
+		"@v/v1.0.0.txt",
+		"",
+		"list",
+	}
+	for _, rest := range invalid {
+		if validProxyRest(rest) {
+			t.Errorf("validProxyRest(%q) = true, want false", rest)
+		}
+	}
+}
+
+func TestDedupeSortVersions(t *testing.T) {
+	got := dedupeSortVersions([]string{"v1.2.0", "v1.0.0", "v1.0.0", "v1.10.0", "v1.2.0"})
+	want := []string{"v1.0.0", "v1.2.0", "v1.10.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupeSortVersions(...) = %v, want %v", got, want)
+	}
+}
+
+func TestPickLatest(t *testing.T) {
+	cases := []struct {
+		versions []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"v1.0.0", "v1.2.0", "v1.1.0"}, "v1.2.0"},
+		{[]string{"v1.0.0-rc.1", "v1.0.0-rc.2"}, "v1.0.0-rc.2"},
+		{[]string{"v1.0.0", "v1.1.0-rc.1"}, "v1.0.0"},
+	}
+	for _, c := range cases {
+		if got := pickLatest(c.versions); got != c.want {
+			t.Errorf("pickLatest(%v) = %q, want %q", c.versions, got, c.want)
+		}
+	}
+}
+
+func TestRequiredBump(t *testing.T) {
+	cases := []struct {
+		added, removed, changed []string
+		want                    string
+	}{
+		{nil, nil, nil, "patch"},
+		{[]string{"pkg.New"}, nil, nil, "minor"},
+		{nil, []string{"pkg.Old"}, nil, "major"},
+		{nil, nil, []string{"pkg.Foo"}, "major"},
+		{[]string{"pkg.New"}, []string{"pkg.Old"}, nil, "major"},
+	}
+	for _, c := range cases {
+		if got := requiredBump(c.added, c.removed, c.changed); got != c.want {
+			t.Errorf("requiredBump(%v, %v, %v) = %q, want %q", c.added, c.removed, c.changed, got, c.want)
+		}
+	}
+}
+
+func TestSemverBump(t *testing.T) {
+	cases := []struct {
+		base, version, want string
+	}{
+		{"v1.0.0", "v1.0.1", "patch"},
+		{"v1.0.0", "v1.1.0", "minor"},
+		{"v1.0.0", "v2.0.0", "major"},
+		{"v1.2.3", "v1.2.3", "patch"},
+	}
+	for _, c := range cases {
+		if got := semverBump(c.base, c.version); got != c.want {
+			t.Errorf("semverBump(%q, %q) = %q, want %q", c.base, c.version, got, c.want)
+		}
+	}
+}
+
+func TestDiffAPI(t *testing.T) {
+	base := map[string]apiSymbol{
+		"pkg.Keep":   {Kind: "func", Sig: "func()"},
+		"pkg.Remove": {Kind: "func", Sig: "func()"},
+		"pkg.Change": {Kind: "func", Sig: "func(int)"},
+	}
+	candidate := map[string]apiSymbol{
+		"pkg.Keep":   {Kind: "func", Sig: "func()"},
+		"pkg.Change": {Kind: "func", Sig: "func(string)"},
+		"pkg.New":    {Kind: "func", Sig: "func()"},
+	}
+	added, removed, changed := diffAPI(base, candidate)
+	if !reflect.DeepEqual(added, []string{"pkg.New"}) {
+		t.Errorf("added = %v, want [pkg.New]", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"pkg.Remove"}) {
+		t.Errorf("removed = %v, want [pkg.Remove]", removed)
+	}
+	if !reflect.DeepEqual(changed, []string{"pkg.Change"}) {
+		t.Errorf("changed = %v, want [pkg.Change]", changed)
+	}
+}
+
+func TestAPIKeyNoLeadingDotForRootPackage(t *testing.T) {
+	if got := apiKey(".", "Foo"); got != "Foo" {
+		t.Errorf("apiKey(%q, %q) = %q, want %q", ".", "Foo", got, "Foo")
+	}
+	if got := apiKey("sub/pkg", "Foo"); got != "sub/pkg.Foo" {
+		t.Errorf("apiKey(%q, %q) = %q, want %q", "sub/pkg", "Foo", got, "sub/pkg.Foo")
+	}
+}
+
+func TestFuncSigIgnoresParameterNames(t *testing.T) {
+	sig := func(src string) string {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "x.go", "package p\nfunc Foo"+src+"{}", 0)
+		if err != nil {
+			t.Fatalf("parse %q: %v", src, err)
+		}
+		decl := f.Decls[0].(*ast.FuncDecl)
+		return funcSig(fset, decl.Type)
+	}
+
+	a := sig("(x int) string")
+	b := sig("(n int) string")
+	if a != b {
+		t.Errorf("funcSig differs for a cosmetic parameter rename: %q vs %q", a, b)
+	}
+
+	c := sig("(n string) string")
+	if a == c {
+		t.Errorf("funcSig did not change for a real parameter type change: %q", a)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectPackageAPISkipsVendor(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/m\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(root, "root.go"), "package m\n\nfunc Root() {}\n")
+	writeFile(t, filepath.Join(root, "vendor", "example.com", "dep", "dep.go"), "package dep\n\nfunc DepFunc() {}\n")
+
+	api, err := collectPackageAPI(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := api["Root"]; !ok {
+		t.Errorf("api = %v, want it to contain the module's own Root func", api)
+	}
+	for key := range api {
+		if key == "vendor/example.com/dep.DepFunc" {
+			t.Errorf("api contains vendored symbol %q; vendor/ must be excluded like zip.CreateFromDir excludes it", key)
+		}
+	}
+}
+
+func TestCollectPackageAPISkipsSubmodules(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/m\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(root, "root.go"), "package m\n\nfunc Root() {}\n")
+	writeFile(t, filepath.Join(root, "sub", "go.mod"), "module example.com/m/sub\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(root, "sub", "sub.go"), "package sub\n\nfunc Sub() {}\n")
+
+	api, err := collectPackageAPI(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := api["Root"]; !ok {
+		t.Errorf("api = %v, want it to contain the root module's own Root func", api)
+	}
+	for key := range api {
+		if key == "sub.Sub" {
+			t.Errorf("api contains submodule symbol %q; a subdirectory with its own go.mod must be excluded like zip.CreateFromDir excludes it", key)
+		}
+	}
+}